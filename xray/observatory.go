@@ -0,0 +1,94 @@
+package xray
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/xtls/xray-core/app/observatory"
+	"github.com/xtls/xray-core/features/extension"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// QueryObservatory returns the current OutboundStatus snapshot (delay, last-seen, alive,
+// OutboundTag) for every outbound probed by the running core's observatory feature.
+//
+// There is no runtime-reconfiguration entry point for the observatory feature in xray-core:
+// probe URL, interval and subject selector are fixed at observatory.New(ctx, config) build
+// time, so this file only exposes read access to whatever observatory the config already set up.
+func QueryObservatory() (string, error) {
+	result, err := observationResult()
+	if err != nil {
+		return "", err
+	}
+	raw, err := protojson.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+// SelectLeastPing returns the tag with the smallest recorded RTT among the given candidate
+// outbound tags (all probed outbounds when tags is empty), ignoring any that aren't alive.
+func SelectLeastPing(tags []string) (string, error) {
+	result, err := observationResult()
+	if err != nil {
+		return "", err
+	}
+	want := make(map[string]bool, len(tags))
+	for _, t := range tags {
+		want[t] = true
+	}
+	best := ""
+	var bestDelay int64
+	for _, status := range result.Status {
+		if len(want) > 0 && !want[status.OutboundTag] {
+			continue
+		}
+		if !status.Alive {
+			continue
+		}
+		if best == "" || status.Delay < bestDelay {
+			best = status.OutboundTag
+			bestDelay = status.Delay
+		}
+	}
+	if best == "" {
+		return "", fmt.Errorf("no alive outbound found among candidates")
+	}
+	return best, nil
+}
+
+// observationResult fetches the running core's observatory feature and asks it for its
+// current observation, same pattern as statsManager in stats.go.
+func observationResult() (*observatory.ObservationResult, error) {
+	ob, err := observatoryFeature()
+	if err != nil {
+		return nil, err
+	}
+	msg, err := ob.GetObservation(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	result, ok := msg.(*observatory.ObservationResult)
+	if !ok {
+		return nil, fmt.Errorf("unexpected observation result type")
+	}
+	return result, nil
+}
+
+// observatoryFeature fetches the running core's extension.Observatory, failing the same way
+// statsManager does when the core isn't up or the feature isn't enabled.
+func observatoryFeature() (extension.Observatory, error) {
+	if coreServer == nil || !coreServer.IsRunning() {
+		return nil, fmt.Errorf("core not running")
+	}
+	f := coreServer.GetFeature(extension.ObservatoryType())
+	if f == nil {
+		return nil, fmt.Errorf("observatory not enabled in config")
+	}
+	ob, ok := f.(extension.Observatory)
+	if !ok {
+		return nil, fmt.Errorf("observatory feature type mismatch")
+	}
+	return ob, nil
+}
@@ -0,0 +1,267 @@
+package xray
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/features/inbound"
+	"github.com/xtls/xray-core/proxy"
+)
+
+// Quota actions recognised by SetUserQuota. "log" only fires the registered callback.
+// "disable" and "kick" are aliases of the same effect: the user is removed from every inbound's
+// auth validator via proxy.UserManager, so no *new* handshake will authenticate as them. Neither
+// one terminates a connection the user already has established — xray-core's inbound handlers
+// don't expose a way to tear down an in-flight session from outside, only to stop accepting new
+// ones. Callers that need a hard, immediate cutoff must close the transport themselves (e.g. by
+// fully restarting the inbound, which does disconnect everyone on it, not just this user).
+const (
+	QuotaActionLog     = "log"
+	QuotaActionDisable = "disable"
+	QuotaActionKick    = "kick"
+)
+
+// quotaPollInterval is how often the background watcher checks usage against configured quotas.
+const quotaPollInterval = 5 * time.Second
+
+// UserQuota is the JSON shape persisted to the sidecar file and returned by GetUserQuota /
+// ListUserQuotas.
+type UserQuota struct {
+	Email    string `json:"email"`
+	Bytes    int64  `json:"bytes"`
+	Action   string `json:"action"`
+	Exceeded bool   `json:"exceeded"`
+}
+
+var (
+	quotasMu       sync.Mutex
+	quotas         = map[string]*UserQuota{}
+	quotaCallbacks []func(email, reason string)
+	quotaStorePath = "xray_quotas.json"
+	quotaLoadOnce  sync.Once
+	quotaWatchOnce sync.Once
+)
+
+// SetQuotaStorePath changes where quotas are persisted, e.g. to a path next to the running
+// config file. Must be called before the first SetUserQuota/GetUserQuota/ListUserQuotas call,
+// since quotas are lazily loaded from quotaStorePath on first access.
+func SetQuotaStorePath(path string) {
+	quotasMu.Lock()
+	quotaStorePath = path
+	quotasMu.Unlock()
+}
+
+// SetUserQuota registers (or updates) a traffic quota for email: once the user's cumulative
+// uplink+downlink crosses bytes, action fires. Quotas are persisted to a JSON sidecar file so
+// they survive restarts, and a single background watcher goroutine is started lazily on first
+// use to poll the user's counter and apply the action. Note that QuotaActionDisable and
+// QuotaActionKick only block the user's *next* handshake; see the doc comment on those
+// constants for why an already-connected user isn't disconnected immediately.
+func SetUserQuota(email string, bytes int64, action string) error {
+	switch action {
+	case QuotaActionLog, QuotaActionDisable, QuotaActionKick:
+	default:
+		return fmt.Errorf("unknown quota action: %s", action)
+	}
+	loadQuotasOnce()
+
+	quotasMu.Lock()
+	quotas[email] = &UserQuota{Email: email, Bytes: bytes, Action: action}
+	err := persistQuotasLocked()
+	quotasMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	ensureQuotaWatcher()
+	return nil
+}
+
+// GetUserQuota returns the JSON-encoded quota for email, or an error if none is set.
+func GetUserQuota(email string) (string, error) {
+	loadQuotasOnce()
+	quotasMu.Lock()
+	q, ok := quotas[email]
+	quotasMu.Unlock()
+	if !ok {
+		return "", fmt.Errorf("no quota set for %s", email)
+	}
+	raw, err := json.Marshal(q)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+// ListUserQuotas returns the JSON-encoded array of every configured quota.
+func ListUserQuotas() (string, error) {
+	loadQuotasOnce()
+	quotasMu.Lock()
+	list := make([]*UserQuota, 0, len(quotas))
+	for _, q := range quotas {
+		list = append(list, q)
+	}
+	quotasMu.Unlock()
+	raw, err := json.Marshal(list)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+// RegisterQuotaCallback registers a callback invoked whenever a user's quota is crossed,
+// after the configured action (if any) has been applied. Multiple callbacks may be registered;
+// each is called for every crossing.
+func RegisterQuotaCallback(cb func(email, reason string)) {
+	quotasMu.Lock()
+	quotaCallbacks = append(quotaCallbacks, cb)
+	quotasMu.Unlock()
+}
+
+// ensureQuotaWatcher starts the background watcher goroutine on first call. It's invoked both
+// from SetUserQuota and from loadQuotasOnce, so a host app that only reads quotas back from the
+// sidecar file after a restart (without calling SetUserQuota again) still gets enforcement.
+func ensureQuotaWatcher() {
+	quotaWatchOnce.Do(func() { go watchQuotas() })
+}
+
+// watchQuotas polls every configured user's traffic counter on quotaPollInterval and applies
+// each quota's action the first time usage crosses its threshold.
+func watchQuotas() {
+	ticker := time.NewTicker(quotaPollInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		manager, err := statsManager()
+		if err != nil {
+			continue
+		}
+		quotasMu.Lock()
+		due := make([]*UserQuota, 0)
+		dirty := false
+		for _, q := range quotas {
+			used := counterValue(manager, fmt.Sprintf("user>>>%s>>>traffic>>>uplink", q.Email)) +
+				counterValue(manager, fmt.Sprintf("user>>>%s>>>traffic>>>downlink", q.Email))
+			switch {
+			case used >= q.Bytes && !q.Exceeded:
+				// Newly over quota: apply the action once, then go quiet until usage drops back
+				// down. A billing cycle that calls ResetStats/ResetAllStats (chunk0-1) zeroes the
+				// underlying counter, so usage naturally falls below q.Bytes again on the next
+				// poll and this re-arms for the new cycle without any extra bookkeeping.
+				q.Exceeded = true
+				due = append(due, q)
+				dirty = true
+			case used < q.Bytes && q.Exceeded:
+				q.Exceeded = false
+				dirty = true
+			}
+		}
+		if dirty {
+			_ = persistQuotasLocked()
+		}
+		quotasMu.Unlock()
+
+		for _, q := range due {
+			applyQuotaAction(q)
+		}
+	}
+}
+
+// applyQuotaAction carries out q's action and notifies any registered callbacks. The reason
+// string deliberately says "blocked", not "disconnected": see the doc comment on
+// QuotaActionDisable/QuotaActionKick for why this doesn't drop an already-established session.
+func applyQuotaAction(q *UserQuota) {
+	reason := "quota exceeded"
+	if q.Action == QuotaActionDisable || q.Action == QuotaActionKick {
+		if err := removeUserFromInbounds(q.Email); err != nil {
+			reason = fmt.Sprintf("quota exceeded, removal failed: %v", err)
+		} else {
+			reason = "quota exceeded, user blocked from new connections"
+		}
+	}
+
+	quotasMu.Lock()
+	callbacks := append([]func(email, reason string){}, quotaCallbacks...)
+	quotasMu.Unlock()
+	for _, cb := range callbacks {
+		cb(q.Email, reason)
+	}
+}
+
+// removeUserFromInbounds walks every inbound handler on the running core and, for each one
+// that exposes a proxy.UserManager, removes email from it so future handshakes as that user
+// fail auth. At least one removal must succeed for this to report success. This does not touch
+// any connection the user already has open.
+func removeUserFromInbounds(email string) error {
+	if coreServer == nil || !coreServer.IsRunning() {
+		return fmt.Errorf("core not running")
+	}
+	f := coreServer.GetFeature(inbound.ManagerType())
+	if f == nil {
+		return fmt.Errorf("inbound manager not available")
+	}
+	manager, ok := f.(inbound.Manager)
+	if !ok {
+		return fmt.Errorf("inbound manager type mismatch")
+	}
+
+	ctx := context.Background()
+	var removed bool
+	for _, handler := range manager.ListHandlers(ctx) {
+		gi, ok := handler.(proxy.GetInbound)
+		if !ok {
+			continue
+		}
+		um, ok := gi.GetInbound().(proxy.UserManager)
+		if !ok {
+			continue
+		}
+		if err := um.RemoveUser(ctx, email); err == nil {
+			removed = true
+		}
+	}
+	if !removed {
+		return fmt.Errorf("user %s not found on any inbound", email)
+	}
+	return nil
+}
+
+// loadQuotasOnce reads quotaStorePath into quotas on first access, so quotas set before a
+// restart are picked back up without callers having to call SetUserQuota again.
+func loadQuotasOnce() {
+	quotaLoadOnce.Do(func() {
+		data, err := os.ReadFile(quotaStorePath)
+		if err != nil {
+			return
+		}
+		var list []*UserQuota
+		if err := json.Unmarshal(data, &list); err != nil {
+			return
+		}
+		quotasMu.Lock()
+		for _, q := range list {
+			quotas[q.Email] = q
+		}
+		quotasMu.Unlock()
+
+		if len(list) > 0 {
+			ensureQuotaWatcher()
+		}
+	})
+}
+
+// persistQuotasLocked writes the current quotas to quotaStorePath. Callers must hold quotasMu.
+func persistQuotasLocked() error {
+	list := make([]*UserQuota, 0, len(quotas))
+	for _, q := range quotas {
+		list = append(list, q)
+	}
+	raw, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(quotaStorePath, raw, 0o644)
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"strings"
 
+	appstats "github.com/xtls/xray-core/app/stats"
 	corestats "github.com/xtls/xray-core/features/stats"
 )
 
@@ -42,36 +43,175 @@ func queryStatsHTTP(server string) (string, error) {
 	return string(body), nil
 }
 
-// queryStatsInProcess reads uplink/downlink from corestats.Manager (same as AndroidLibXrayLite).
-// Counter names: outbound>>>tag>>>traffic>>>uplink / downlink. Value() returns cumulative bytes.
-func queryStatsInProcess(tag string) (string, error) {
-	if tag == "" {
-		return "", fmt.Errorf("stats tag is empty")
+// QueryAllStats walks every counter registered in corestats.Manager and groups them by kind
+// (inbound/outbound/user), the same traversal the upstream metrics app does via VisitCounters.
+// Returns JSON: {"inbound":{tag:{"uplink":n,"downlink":n}},"outbound":{...},"user":{email:{...}}}.
+func QueryAllStats() (string, error) {
+	manager, err := visitableStatsManager()
+	if err != nil {
+		return "", err
+	}
+	out := map[string]map[string]*trafficStat{
+		"inbound":  {},
+		"outbound": {},
+		"user":     {},
+	}
+	manager.VisitCounters(func(name string, c corestats.Counter) bool {
+		kind, tag, direction, ok := parseCounterName(name)
+		if !ok {
+			return true
+		}
+		group, ok := out[kind]
+		if !ok {
+			return true
+		}
+		stat, ok := group[tag]
+		if !ok {
+			stat = &trafficStat{}
+			group[tag] = stat
+		}
+		switch direction {
+		case "uplink":
+			stat.Uplink = c.Value()
+		case "downlink":
+			stat.Downlink = c.Value()
+		}
+		return true
+	})
+	raw, err := json.Marshal(out)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+// QueryStatsByKind returns uplink/downlink for a single counter identified by kind
+// ("inbound", "outbound" or "user") and tag (an inbound/outbound tag, or a user email).
+func QueryStatsByKind(kind string, tag string) (string, error) {
+	if kind != "inbound" && kind != "outbound" && kind != "user" {
+		return "", fmt.Errorf("unknown stats kind: %s", kind)
 	}
+	manager, err := statsManager()
+	if err != nil {
+		return "", err
+	}
+	stat := &trafficStat{
+		Uplink:   counterValue(manager, fmt.Sprintf("%s>>>%s>>>traffic>>>uplink", kind, tag)),
+		Downlink: counterValue(manager, fmt.Sprintf("%s>>>%s>>>traffic>>>downlink", kind, tag)),
+	}
+	raw, err := json.Marshal(stat)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+// ResetStats zeroes the uplink/downlink counters for a single "kind>>>tag" pair, e.g.
+// "outbound>>>proxy" or "user>>>alice@example.com". Lets callers implement billing cycles
+// without restarting the core.
+func ResetStats(name string) error {
+	manager, err := statsManager()
+	if err != nil {
+		return err
+	}
+	resetCounter(manager, fmt.Sprintf("%s>>>traffic>>>uplink", name))
+	resetCounter(manager, fmt.Sprintf("%s>>>traffic>>>downlink", name))
+	return nil
+}
+
+// ResetAllStats zeroes every counter currently registered in corestats.Manager.
+func ResetAllStats() error {
+	manager, err := visitableStatsManager()
+	if err != nil {
+		return err
+	}
+	manager.VisitCounters(func(name string, c corestats.Counter) bool {
+		c.Set(0)
+		return true
+	})
+	return nil
+}
+
+// trafficStat is the JSON shape shared by QueryAllStats and QueryStatsByKind.
+type trafficStat struct {
+	Uplink   int64 `json:"uplink"`
+	Downlink int64 `json:"downlink"`
+}
+
+// statsManager fetches the running core's corestats.Manager, failing the same way
+// queryStatsInProcess does when the core isn't up or stats aren't enabled.
+func statsManager() (corestats.Manager, error) {
 	if coreServer == nil || !coreServer.IsRunning() {
-		return "", fmt.Errorf("core not running")
+		return nil, fmt.Errorf("core not running")
 	}
-	m := coreServer.GetFeature(corestats.ManagerType())
-	if m == nil {
-		return "", fmt.Errorf("stats not enabled in config")
+	f := coreServer.GetFeature(corestats.ManagerType())
+	if f == nil {
+		return nil, fmt.Errorf("stats not enabled in config")
 	}
-	manager, ok := m.(corestats.Manager)
+	manager, ok := f.(corestats.Manager)
 	if !ok {
-		return "", fmt.Errorf("stats manager type mismatch")
+		return nil, fmt.Errorf("stats manager type mismatch")
+	}
+	return manager, nil
+}
+
+// visitableStatsManager is statsManager, but additionally asserts down to the concrete
+// *app/stats.Manager, which is what actually implements VisitCounters — the features/stats.Manager
+// interface only exposes GetCounter/RegisterCounter/UnregisterCounter. This mirrors how upstream's
+// own metrics app (app/metrics/metrics.go) gets at VisitCounters.
+func visitableStatsManager() (*appstats.Manager, error) {
+	manager, err := statsManager()
+	if err != nil {
+		return nil, err
+	}
+	concrete, ok := manager.(*appstats.Manager)
+	if !ok {
+		return nil, fmt.Errorf("stats manager does not support counter enumeration")
+	}
+	return concrete, nil
+}
+
+// parseCounterName splits a "kind>>>tag>>>traffic>>>direction" counter name, as produced by
+// xray-core's stats policy, into its parts.
+func parseCounterName(name string) (kind, tag, direction string, ok bool) {
+	parts := strings.Split(name, ">>>")
+	if len(parts) != 4 || parts[2] != "traffic" {
+		return "", "", "", false
+	}
+	return parts[0], parts[1], parts[3], true
+}
+
+// counterValue returns 0 for a counter that doesn't exist rather than erroring, matching
+// queryStatsInProcess's behaviour for missing tags.
+func counterValue(manager corestats.Manager, name string) int64 {
+	c := manager.GetCounter(name)
+	if c == nil {
+		return 0
+	}
+	return c.Value()
+}
+
+// resetCounter is a no-op if the counter doesn't exist.
+func resetCounter(manager corestats.Manager, name string) {
+	if c := manager.GetCounter(name); c != nil {
+		c.Set(0)
 	}
-	upCounter := manager.GetCounter(fmt.Sprintf("outbound>>>%s>>>traffic>>>uplink", tag))
-	downCounter := manager.GetCounter(fmt.Sprintf("outbound>>>%s>>>traffic>>>downlink", tag))
-	var uplink, downlink int64
-	if upCounter != nil {
-		uplink = upCounter.Value()
+}
+
+// queryStatsInProcess reads uplink/downlink from corestats.Manager (same as AndroidLibXrayLite).
+// Counter names: outbound>>>tag>>>traffic>>>uplink / downlink. Value() returns cumulative bytes.
+func queryStatsInProcess(tag string) (string, error) {
+	if tag == "" {
+		return "", fmt.Errorf("stats tag is empty")
+	}
+	manager, err := statsManager()
+	if err != nil {
+		return "", err
 	}
-	if downCounter != nil {
-		downlink = downCounter.Value()
+	out := &trafficStat{
+		Uplink:   counterValue(manager, fmt.Sprintf("outbound>>>%s>>>traffic>>>uplink", tag)),
+		Downlink: counterValue(manager, fmt.Sprintf("outbound>>>%s>>>traffic>>>downlink", tag)),
 	}
-	out := struct {
-		Uplink   int64 `json:"uplink"`
-		Downlink int64 `json:"downlink"`
-	}{Uplink: uplink, Downlink: downlink}
 	raw, err := json.Marshal(out)
 	if err != nil {
 		return "", err
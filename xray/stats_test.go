@@ -0,0 +1,34 @@
+package xray
+
+import "testing"
+
+func TestParseCounterName(t *testing.T) {
+	cases := []struct {
+		name     string
+		wantKind string
+		wantTag  string
+		wantDir  string
+		wantOK   bool
+	}{
+		{"outbound>>>proxy>>>traffic>>>uplink", "outbound", "proxy", "uplink", true},
+		{"inbound>>>socks-in>>>traffic>>>downlink", "inbound", "socks-in", "downlink", true},
+		{"user>>>alice@example.com>>>traffic>>>uplink", "user", "alice@example.com", "uplink", true},
+		{"outbound>>>proxy>>>traffic", "", "", "", false},
+		{"outbound>>>proxy>>>latency>>>uplink", "", "", "", false},
+		{"", "", "", "", false},
+	}
+	for _, c := range cases {
+		kind, tag, direction, ok := parseCounterName(c.name)
+		if ok != c.wantOK {
+			t.Errorf("parseCounterName(%q) ok = %v, want %v", c.name, ok, c.wantOK)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if kind != c.wantKind || tag != c.wantTag || direction != c.wantDir {
+			t.Errorf("parseCounterName(%q) = (%q, %q, %q), want (%q, %q, %q)",
+				c.name, kind, tag, direction, c.wantKind, c.wantTag, c.wantDir)
+		}
+	}
+}
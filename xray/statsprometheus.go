@@ -0,0 +1,93 @@
+package xray
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	corestats "github.com/xtls/xray-core/features/stats"
+)
+
+// QueryStatsPrometheus renders every counter in corestats.Manager, plus the observatory's
+// per-outbound RTT and last-alive timestamp, in Prometheus text exposition format. This gives
+// users a scrape target without enabling xray's expvar endpoint, which the comments on QueryStats
+// and QueryStatsByTag already warn can crash when metrics isn't configured.
+func QueryStatsPrometheus() (string, error) {
+	manager, err := visitableStatsManager()
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	b.WriteString("# HELP xray_traffic_bytes_total Cumulative traffic in bytes.\n")
+	b.WriteString("# TYPE xray_traffic_bytes_total counter\n")
+	manager.VisitCounters(func(name string, c corestats.Counter) bool {
+		kind, tag, direction, ok := parseCounterName(name)
+		if !ok {
+			return true
+		}
+		b.WriteString(promTrafficLine(direction, kind, tag, c.Value()))
+		return true
+	})
+
+	if result, err := observationResult(); err == nil {
+		b.WriteString("# HELP xray_outbound_rtt_seconds Last observed RTT for an outbound, in seconds.\n")
+		b.WriteString("# TYPE xray_outbound_rtt_seconds gauge\n")
+		for _, status := range result.Status {
+			fmt.Fprintf(&b, "xray_outbound_rtt_seconds{tag=%q} %f\n",
+				status.OutboundTag, float64(status.Delay)/1000)
+		}
+		b.WriteString("# HELP xray_outbound_last_seen_timestamp_seconds Unix timestamp the outbound was last seen alive.\n")
+		b.WriteString("# TYPE xray_outbound_last_seen_timestamp_seconds gauge\n")
+		for _, status := range result.Status {
+			fmt.Fprintf(&b, "xray_outbound_last_seen_timestamp_seconds{tag=%q} %d\n",
+				status.OutboundTag, status.LastSeenTime)
+		}
+	}
+
+	return b.String(), nil
+}
+
+// promTrafficLine renders the xray_traffic_bytes_total sample line for one parsed counter.
+func promTrafficLine(direction, kind, tag string, value int64) string {
+	return fmt.Sprintf("xray_traffic_bytes_total{direction=%q,kind=%q,tag=%q} %d\n",
+		promDirection(direction), kind, tag, value)
+}
+
+// promDirection maps the "uplink"/"downlink" counter naming convention onto the "up"/"down"
+// label values requested for xray_traffic_bytes_total.
+func promDirection(direction string) string {
+	switch direction {
+	case "uplink":
+		return "up"
+	case "downlink":
+		return "down"
+	default:
+		return direction
+	}
+}
+
+// StartPrometheusListener starts an HTTP server on addr that serves QueryStatsPrometheus's
+// output on "/metrics", for tools that scrape rather than call into libXray directly. It returns
+// once the listener is up; the server itself runs in the background for the life of the process.
+func StartPrometheusListener(addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		body, err := QueryStatsPrometheus()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		_, _ = w.Write([]byte(body))
+	})
+	server := &http.Server{Addr: addr, Handler: mux}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("prometheus listener: %w", err)
+	}
+	go func() {
+		_ = server.Serve(ln)
+	}()
+	return nil
+}
@@ -0,0 +1,34 @@
+package xray
+
+import (
+	"testing"
+)
+
+func TestPromDirection(t *testing.T) {
+	cases := []struct {
+		direction string
+		want      string
+	}{
+		{"uplink", "up"},
+		{"downlink", "down"},
+		{"sideways", "sideways"}, // unknown values pass through unchanged
+		{"", ""},
+	}
+	for _, c := range cases {
+		if got := promDirection(c.direction); got != c.want {
+			t.Errorf("promDirection(%q) = %q, want %q", c.direction, got, c.want)
+		}
+	}
+}
+
+// TestTrafficBytesTotalLineFormat pins the exact exposition-format line QueryStatsPrometheus
+// builds for one counter, so a change to the label set or value formatting doesn't slip by
+// unnoticed.
+func TestTrafficBytesTotalLineFormat(t *testing.T) {
+	got := promTrafficLine("uplink", "outbound", "proxy", 12345)
+
+	want := `xray_traffic_bytes_total{direction="up",kind="outbound",tag="proxy"} 12345` + "\n"
+	if got != want {
+		t.Errorf("line = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,190 @@
+package xray
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	corestats "github.com/xtls/xray-core/features/stats"
+)
+
+const (
+	// rateSampleInterval is how often a sampler goroutine takes a new (timestamp, value) reading.
+	// Subscribers asking for different windowSeconds all share this one interval (coalesced).
+	rateSampleInterval = 1 * time.Second
+	// rateRingSize bounds how much history a sampler keeps, regardless of window size requested.
+	rateRingSize = 300 // 5 minutes at 1 sample/second
+	// rateIdleTimeout is how long a sampler keeps running after its last QueryRate poll before
+	// it tears itself down.
+	rateIdleTimeout = 30 * time.Second
+)
+
+// rateSample is one (timestamp, cumulative value) reading for an outbound's uplink or downlink
+// counter.
+type rateSample struct {
+	at   time.Time
+	up   int64
+	down int64
+}
+
+// rateSampler polls one outbound tag's uplink/downlink counters on a fixed interval and keeps a
+// bounded ring of samples so QueryRate can compute a moving average without every caller having
+// to poll and diff the cumulative counters itself.
+type rateSampler struct {
+	mu         sync.Mutex
+	tag        string
+	ring       []rateSample
+	lastPolled time.Time
+	stop       chan struct{}
+}
+
+var (
+	ratesMu sync.Mutex
+	rates   = map[string]*rateSampler{}
+)
+
+// QueryRate returns the simple moving average uplink/downlink throughput (bytes/sec) for the
+// given outbound tag over the trailing windowSeconds, lazily starting a sampler for the tag on
+// first call. Callers should poll at roughly windowSeconds/2 or finer; a tag's sampler shuts
+// itself down after rateIdleTimeout without a poll.
+func QueryRate(tag string, windowSeconds int) (string, error) {
+	if tag == "" {
+		return "", fmt.Errorf("stats tag is empty")
+	}
+	if windowSeconds <= 0 {
+		return "", fmt.Errorf("windowSeconds must be positive")
+	}
+	sampler := sharedRateSampler(tag)
+	uplinkBps, downlinkBps, samples := sampler.rate(time.Duration(windowSeconds) * time.Second)
+	out := struct {
+		UplinkBps   float64 `json:"uplinkBps"`
+		DownlinkBps float64 `json:"downlinkBps"`
+		Samples     int     `json:"samples"`
+	}{uplinkBps, downlinkBps, samples}
+	raw, err := json.Marshal(out)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+// sharedRateSampler returns the running sampler for tag, starting one if this is the first
+// subscriber.
+func sharedRateSampler(tag string) *rateSampler {
+	ratesMu.Lock()
+	defer ratesMu.Unlock()
+	if s, ok := rates[tag]; ok {
+		return s
+	}
+	s := &rateSampler{tag: tag, stop: make(chan struct{})}
+	rates[tag] = s
+	go s.run()
+	return s
+}
+
+// StopRateSampler force-retires tag's sampler goroutine immediately, without waiting out
+// rateIdleTimeout. Useful when a caller knows a tag will never be polled again (e.g. the
+// outbound was removed from the config) and wants to free it right away. A no-op if tag has no
+// running sampler.
+func StopRateSampler(tag string) {
+	ratesMu.Lock()
+	s, ok := rates[tag]
+	if ok {
+		delete(rates, tag)
+	}
+	ratesMu.Unlock()
+	if ok {
+		close(s.stop)
+	}
+}
+
+func (s *rateSampler) run() {
+	ticker := time.NewTicker(rateSampleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.poll()
+			if s.idle() {
+				ratesMu.Lock()
+				delete(rates, s.tag)
+				ratesMu.Unlock()
+				return
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// poll re-resolves the counters by name on every tick (rather than caching the Counter, which
+// may belong to a core instance that has since been torn down) so the sampler is safe across a
+// core restart; if the counters are gone, or have gone backwards since the last sample, it
+// invalidates the ring instead of sampling garbage.
+func (s *rateSampler) poll() {
+	var upCounter, downCounter corestats.Counter
+	if manager, err := statsManager(); err == nil {
+		upCounter = manager.GetCounter(fmt.Sprintf("outbound>>>%s>>>traffic>>>uplink", s.tag))
+		downCounter = manager.GetCounter(fmt.Sprintf("outbound>>>%s>>>traffic>>>downlink", s.tag))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if upCounter == nil || downCounter == nil {
+		s.ring = nil
+		return
+	}
+	sample := rateSample{at: time.Now(), up: upCounter.Value(), down: downCounter.Value()}
+	if n := len(s.ring); n > 0 && (sample.up < s.ring[n-1].up || sample.down < s.ring[n-1].down) {
+		s.ring = nil
+	}
+	s.ring = append(s.ring, sample)
+	if len(s.ring) > rateRingSize {
+		s.ring = s.ring[len(s.ring)-rateRingSize:]
+	}
+}
+
+func (s *rateSampler) idle() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.lastPolled.IsZero() && time.Since(s.lastPolled) > rateIdleTimeout
+}
+
+// rate computes the simple moving average uplink/downlink bytes/sec across ring samples that
+// fall within window, by averaging the per-interval rate between consecutive samples.
+func (s *rateSampler) rate(window time.Duration) (uplinkBps, downlinkBps float64, samples int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastPolled = time.Now()
+
+	cutoff := time.Now().Add(-window)
+	var start int
+	for start = 0; start < len(s.ring); start++ {
+		if s.ring[start].at.After(cutoff) {
+			break
+		}
+	}
+	if start > 0 {
+		start-- // keep one sample before the cutoff so the first interval in-window has a baseline
+	}
+	inWindow := s.ring[start:]
+	if len(inWindow) < 2 {
+		return 0, 0, len(inWindow)
+	}
+
+	var upRateSum, downRateSum float64
+	var intervals int
+	for i := 1; i < len(inWindow); i++ {
+		dt := inWindow[i].at.Sub(inWindow[i-1].at).Seconds()
+		if dt <= 0 {
+			continue
+		}
+		upRateSum += float64(inWindow[i].up-inWindow[i-1].up) / dt
+		downRateSum += float64(inWindow[i].down-inWindow[i-1].down) / dt
+		intervals++
+	}
+	if intervals == 0 {
+		return 0, 0, len(inWindow)
+	}
+	return upRateSum / float64(intervals), downRateSum / float64(intervals), len(inWindow)
+}
@@ -0,0 +1,62 @@
+package xray
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateSamplerRate(t *testing.T) {
+	// rate()'s window is measured back from time.Now(), so the fixture has to be anchored to
+	// "now" rather than a fixed timestamp, or a large window would see nothing but the single
+	// most recent sample.
+	now := time.Now()
+	base := now.Add(-3 * time.Second)
+	s := &rateSampler{
+		tag: "proxy",
+		ring: []rateSample{
+			{at: base, up: 0, down: 0},
+			{at: base.Add(1 * time.Second), up: 100, down: 200},
+			{at: base.Add(2 * time.Second), up: 200, down: 400},
+			{at: base.Add(3 * time.Second), up: 300, down: 600},
+		},
+	}
+	uplinkBps, downlinkBps, samples := s.rate(24 * time.Hour)
+
+	if samples != 4 {
+		t.Errorf("samples = %d, want 4", samples)
+	}
+	if uplinkBps != 100 {
+		t.Errorf("uplinkBps = %v, want 100 (three 1s intervals of +100 bytes each)", uplinkBps)
+	}
+	if downlinkBps != 200 {
+		t.Errorf("downlinkBps = %v, want 200 (three 1s intervals of +200 bytes each)", downlinkBps)
+	}
+}
+
+func TestRateSamplerRateTooFewSamples(t *testing.T) {
+	s := &rateSampler{tag: "proxy", ring: []rateSample{{at: time.Now(), up: 10, down: 20}}}
+	uplinkBps, downlinkBps, samples := s.rate(time.Minute)
+	if uplinkBps != 0 || downlinkBps != 0 {
+		t.Errorf("rate with <2 samples should be zero, got (%v, %v)", uplinkBps, downlinkBps)
+	}
+	if samples != 1 {
+		t.Errorf("samples = %d, want 1", samples)
+	}
+}
+
+func TestRateSamplerRateWindowExcludesOldSamples(t *testing.T) {
+	now := time.Now()
+	s := &rateSampler{
+		tag: "proxy",
+		ring: []rateSample{
+			{at: now.Add(-10 * time.Minute), up: 0, down: 0},
+			{at: now.Add(-9 * time.Minute), up: 1_000_000, down: 0}, // huge jump outside the window
+			{at: now.Add(-1 * time.Second), up: 1_000_100, down: 100},
+			{at: now, up: 1_000_200, down: 200},
+		},
+	}
+	uplinkBps, _, _ := s.rate(5 * time.Second)
+	if uplinkBps > 1000 {
+		t.Errorf("uplinkBps = %v, want the old 1MB jump excluded from a 5s window", uplinkBps)
+	}
+}